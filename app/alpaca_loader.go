@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"math/rand"
 	"strings"
 	"sync"
 	"time"
@@ -11,6 +12,12 @@ import (
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata/stream"
 )
 
+// randFloat returns a random float64 in [0, 1), isolated behind a helper so
+// backoffDelay's jitter math reads clearly at the call site.
+func randFloat() float64 {
+	return rand.Float64()
+}
+
 // DataClient interface
 type DataClient interface {
 	GetHistory(symbols []string, nDays int, interval string) (interface{}, error)
@@ -20,7 +27,8 @@ type DataClient interface {
 
 // StockClient
 type StockClient struct {
-	client *marketdata.Client
+	client   *marketdata.Client
+	calendar *MarketCalendar
 }
 
 func NewStockClient() *StockClient {
@@ -30,27 +38,50 @@ func NewStockClient() *StockClient {
 			APISecret: AlpacaSecret,
 			Feed:      marketdata.IEX, // Matching feed=DataFeed.IEX from Python
 		}),
+		calendar: NewMarketCalendar(),
 	}
 }
 
+// UsTradingHours returns the UTC session bounds for day, accounting for
+// weekends, holidays, and 1:00 PM ET half days via sc.calendar.
 func (sc *StockClient) UsTradingHours(day time.Time) (time.Time, time.Time) {
-	// Ensure day is in UTC
-	day = day.UTC()
-
-	// Create start and end times
-	// Python: datetime(day.year, day.month, day.day, 13, 30, tzinfo=timezone.utc) # 9:30 AM ET -> 13:30 UTC
-	// Python: end = 20:00 UTC -> 4:00 PM ET
-
-	start := time.Date(day.Year(), day.Month(), day.Day(), 13, 30, 0, 0, time.UTC)
-	end := time.Date(day.Year(), day.Month(), day.Day(), 20, 0, 0, 0, time.UTC)
-
-	return start, end
+	open, close, _ := sc.calendar.SessionBounds(day.UTC())
+	return open, close
 }
 
 // GetHistory fetches bar history
 // Note: Python returns pd.DataFrame. Go will return []marketdata.Bar (or a struct wrapping it),
 // as DataFrame isn't a native Go construct.
 func (sc *StockClient) GetHistory(symbols []string, lookback int, end *time.Time, interval string) (map[string][]marketdata.Bar, error) {
+	return sc.GetHistoryCtx(context.Background(), symbols, lookback, end, interval)
+}
+
+// stockHistoryPageDays bounds how much wall-clock range a single
+// GetMultiBars call covers, so GetHistoryCtx has a chance to notice
+// ctx cancellation between pages instead of blocking for the whole
+// lookback window in one request.
+const stockHistoryPageDays = 30
+
+// pageRanges splits [start, end) into pageDays-sized [pageStart, pageEnd)
+// windows, clipping the final window to end. Shared by GetHistoryCtx's
+// paging loop so the split itself is testable without a network call.
+func pageRanges(start, end time.Time, pageDays int) [][2]time.Time {
+	var ranges [][2]time.Time
+	for pageStart := start; pageStart.Before(end); pageStart = pageStart.AddDate(0, 0, pageDays) {
+		pageEnd := pageStart.AddDate(0, 0, pageDays)
+		if pageEnd.After(end) {
+			pageEnd = end
+		}
+		ranges = append(ranges, [2]time.Time{pageStart, pageEnd})
+	}
+	return ranges
+}
+
+// GetHistoryCtx is the context-aware form of GetHistory. It splits the
+// [tradingStart, tradingEnd] window into stockHistoryPageDays-sized pages
+// and checks ctx.Done() between each one, so a caller's deadline or
+// cancellation aborts the fetch instead of waiting out every page.
+func (sc *StockClient) GetHistoryCtx(ctx context.Context, symbols []string, lookback int, end *time.Time, interval string) (map[string][]marketdata.Bar, error) {
 	var endTime time.Time
 	if end == nil {
 		endTime = time.Now().UTC()
@@ -58,9 +89,21 @@ func (sc *StockClient) GetHistory(symbols []string, lookback int, end *time.Time
 		endTime = *end
 	}
 
-	_, tradingEnd := sc.UsTradingHours(endTime)
-	// lookback days ago
-	tradingStart, _ := sc.UsTradingHours(endTime.AddDate(0, 0, -lookback))
+	// Shrink the end time to the last actual close at or before endTime.
+	tradingEnd := sc.calendar.PreviousClose(endTime)
+	if tradingEnd.IsZero() {
+		tradingEnd = endTime
+	}
+
+	// Walk back `lookback` trading days (not raw calendar days) from tradingEnd.
+	tradingDay := tradingEnd
+	for i := 0; i < lookback; i++ {
+		tradingDay = sc.calendar.PreviousTradingDay(tradingDay)
+	}
+	tradingStart := tradingEnd
+	if open, _, _ := sc.calendar.SessionBounds(tradingDay); !open.IsZero() {
+		tradingStart = open
+	}
 
 	var timeframe marketdata.TimeFrame
 	switch strings.ToLower(interval) {
@@ -72,24 +115,37 @@ func (sc *StockClient) GetHistory(symbols []string, lookback int, end *time.Time
 		timeframe = marketdata.OneDay
 	}
 
-	req := marketdata.GetBarsRequest{
-		TimeFrame: timeframe,
-		Start:     tradingStart,
-		End:       tradingEnd,
-		Feed:      marketdata.IEX,
-	}
+	out := make(map[string][]marketdata.Bar)
+	for _, pr := range pageRanges(tradingStart, tradingEnd, stockHistoryPageDays) {
+		select {
+		case <-ctx.Done():
+			return out, ctx.Err()
+		default:
+		}
+
+		req := marketdata.GetBarsRequest{
+			TimeFrame: timeframe,
+			Start:     pr[0],
+			End:       pr[1],
+			Feed:      marketdata.IEX,
+		}
 
-	bars, err := sc.client.GetMultiBars(symbols, req)
-	if err != nil {
-		return nil, err
+		bars, err := sc.client.GetMultiBars(symbols, req)
+		if err != nil {
+			return nil, err
+		}
+		for symbol, symbolBars := range bars {
+			out[symbol] = append(out[symbol], symbolBars...)
+		}
 	}
 
-	return bars, nil
+	return out, nil
 }
 
 // OptionsClient
 type OptionsClient struct {
-	client *marketdata.Client
+	client   *marketdata.Client
+	calendar *MarketCalendar
 }
 
 func NewOptionsClient() *OptionsClient {
@@ -98,22 +154,41 @@ func NewOptionsClient() *OptionsClient {
 			APIKey:    AlpacaKey,
 			APISecret: AlpacaSecret,
 		}),
+		calendar: NewMarketCalendar(),
 	}
 }
 
+// UsTradingHours returns the UTC session bounds for day, accounting for
+// weekends, holidays, and 1:00 PM ET half days via oc.calendar.
 func (oc *OptionsClient) UsTradingHours(day time.Time) (time.Time, time.Time) {
-	day = day.UTC()
-	start := time.Date(day.Year(), day.Month(), day.Day(), 13, 30, 0, 0, time.UTC)
-	end := time.Date(day.Year(), day.Month(), day.Day(), 20, 0, 0, 0, time.UTC)
-	return start, end
+	open, close, _ := oc.calendar.SessionBounds(day.UTC())
+	return open, close
 }
 
 func (oc *OptionsClient) GetHistory(symbols []string, nDays int, interval string) (map[string][]marketdata.OptionBar, error) {
-	day := time.Now().UTC().AddDate(0, 0, -nDays)
+	return oc.GetHistoryCtx(context.Background(), symbols, nDays, interval)
+}
+
+// optionsHistoryPageDays mirrors stockHistoryPageDays for option bar pages.
+const optionsHistoryPageDays = 30
+
+// GetHistoryCtx is the context-aware form of GetHistory. It pages the
+// request in optionsHistoryPageDays-sized chunks and aborts between pages
+// if ctx is done.
+func (oc *OptionsClient) GetHistoryCtx(ctx context.Context, symbols []string, nDays int, interval string) (map[string][]marketdata.OptionBar, error) {
+	// Walk back nDays *trading* days rather than raw calendar days.
+	day := time.Now().UTC()
+	for i := 0; i < nDays; i++ {
+		day = oc.calendar.PreviousTradingDay(day)
+	}
 	start, _ := oc.UsTradingHours(day)
 
-	// replicate end time logic: now - 16 mins
-	end := time.Now().UTC().Add(-16 * time.Minute)
+	// Shrink the end time to the last actual close, replicating the
+	// original "now - 16 mins" slack to account for Alpaca's reporting lag.
+	end := oc.calendar.PreviousClose(time.Now().UTC().Add(-16 * time.Minute))
+	if end.IsZero() {
+		end = time.Now().UTC().Add(-16 * time.Minute)
+	}
 
 	var timeframe marketdata.TimeFrame
 	switch strings.ToLower(interval) {
@@ -125,41 +200,184 @@ func (oc *OptionsClient) GetHistory(symbols []string, nDays int, interval string
 		timeframe = marketdata.OneDay
 	}
 
-	req := marketdata.GetOptionBarsRequest{
-		TimeFrame: timeframe,
-		Start:     start,
-		End:       end,
-		// Feed removed as it is not in GetOptionBarsRequest
-	}
+	out := make(map[string][]marketdata.OptionBar)
+	for pageStart := start; pageStart.Before(end); pageStart = pageStart.AddDate(0, 0, optionsHistoryPageDays) {
+		select {
+		case <-ctx.Done():
+			return out, ctx.Err()
+		default:
+		}
+
+		pageEnd := pageStart.AddDate(0, 0, optionsHistoryPageDays)
+		if pageEnd.After(end) {
+			pageEnd = end
+		}
+
+		req := marketdata.GetOptionBarsRequest{
+			TimeFrame: timeframe,
+			Start:     pageStart,
+			End:       pageEnd,
+			// Feed removed as it is not in GetOptionBarsRequest
+		}
 
-	bars, err := oc.client.GetMultiOptionBars(symbols, req)
-	if err != nil {
-		return nil, err
+		bars, err := oc.client.GetMultiOptionBars(symbols, req)
+		if err != nil {
+			return nil, err
+		}
+		for symbol, symbolBars := range bars {
+			out[symbol] = append(out[symbol], symbolBars...)
+		}
 	}
 
-	return bars, nil
+	return out, nil
+}
+
+// ReconnectConfig controls LiveDataClient's exponential-backoff auto-reconnect.
+type ReconnectConfig struct {
+	InitialDelay time.Duration // delay before the first reconnect attempt
+	MaxDelay     time.Duration // backoff ceiling
+	Jitter       float64       // +/- fraction of the delay to randomize, e.g. 0.2
+	MaxAttempts  int           // 0 means retry forever
+}
+
+// DefaultReconnectConfig mirrors what a reasonable long-running consumer of
+// Alpaca's IEX feed wants: start quick, back off to 30s, retry forever.
+var DefaultReconnectConfig = ReconnectConfig{
+	InitialDelay: time.Second,
+	MaxDelay:     30 * time.Second,
+	Jitter:       0.2,
+	MaxAttempts:  0,
 }
 
 type LiveDataClient struct {
 	client    *stream.StocksClient
+	clientMut sync.RWMutex
 	latest    *stream.Quote
 	latestMut sync.RWMutex
-	ctx       context.Context
-	cancel    context.CancelFunc
+	// latestBySymbol tracks the most recent quote per symbol, since a single
+	// GetQuote/GetQuoteManaged subscription can cover many symbols and
+	// quoteHandler is shared across all of them. Guarded by latestMut.
+	latestBySymbol map[string]*stream.Quote
+	lastMsg        time.Time
+	ctx            context.Context
+	cancel         context.CancelFunc
+
+	readDeadline  time.Duration // max silence before Connect is torn down and retried
+	writeDeadline time.Duration // max time to wait for the first quote after Connect
+	reconnect     ReconnectConfig
+
+	healthMut sync.RWMutex
+	connected bool
+	lastErr   error
+
+	wg       sync.WaitGroup
+	doneCh   chan struct{}
+	stopOnce sync.Once
+
+	// multi backs the OnStockQuote/OnCryptoTrade/etc. multi-asset
+	// subscription API (see multi_stream.go), created lazily on first use.
+	multi     *multiStreamState
+	multiOnce sync.Once
 }
 
 func NewLiveDataClient() *LiveDataClient {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &LiveDataClient{
-		ctx:    ctx,
-		cancel: cancel,
+		ctx:            ctx,
+		cancel:         cancel,
+		reconnect:      DefaultReconnectConfig,
+		doneCh:         make(chan struct{}),
+		latestBySymbol: make(map[string]*stream.Quote),
 	}
 }
 
+// SetReconnectConfig overrides the default exponential-backoff behavior used
+// between reconnect attempts.
+func (lc *LiveDataClient) SetReconnectConfig(cfg ReconnectConfig) {
+	lc.reconnect = cfg
+}
+
+// Health reports whether the stream is currently connected, the time of the
+// last quote received, and the last error encountered (if any).
+func (lc *LiveDataClient) Health() (connected bool, lastMsg time.Time, err error) {
+	lc.healthMut.RLock()
+	defer lc.healthMut.RUnlock()
+	lc.latestMut.RLock()
+	defer lc.latestMut.RUnlock()
+	return lc.connected, lc.lastMsg, lc.lastErr
+}
+
+// Done returns a channel that closes once Stop has torn down the stream,
+// drained the handler goroutine, and unsubscribed cleanly.
+func (lc *LiveDataClient) Done() <-chan struct{} {
+	return lc.doneCh
+}
+
+func (lc *LiveDataClient) setHealth(connected bool, err error) {
+	lc.healthMut.Lock()
+	defer lc.healthMut.Unlock()
+	lc.connected = connected
+	lc.lastErr = err
+}
+
+// setClient and getClient guard lc.client, which is written by connectOnce
+// on every (re)connect and read by GetQuoteManaged's diff-handling goroutine;
+// without a lock those two goroutines race.
+func (lc *LiveDataClient) setClient(c *stream.StocksClient) {
+	lc.clientMut.Lock()
+	defer lc.clientMut.Unlock()
+	lc.client = c
+}
+
+func (lc *LiveDataClient) getClient() *stream.StocksClient {
+	lc.clientMut.RLock()
+	defer lc.clientMut.RUnlock()
+	return lc.client
+}
+
+// backoffDelay returns the jittered exponential delay for the given
+// (0-indexed) attempt number. The exponential delay is capped at
+// cfg.MaxDelay before jitter is applied, so the result can exceed
+// cfg.MaxDelay by up to cfg.Jitter of it.
+func backoffDelay(cfg ReconnectConfig, attempt int) time.Duration {
+	delay := cfg.InitialDelay
+	for i := 0; i < attempt; i++ {
+		delay *= 2
+		if delay > cfg.MaxDelay {
+			delay = cfg.MaxDelay
+			break
+		}
+	}
+	if cfg.Jitter > 0 {
+		delta := float64(delay) * cfg.Jitter * (2*randFloat() - 1)
+		delay += time.Duration(delta)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
+
+// SetReadDeadline bounds how long GetQuoteCtx will tolerate silence from the
+// stream (no quotes at all) before tearing down and reconnecting. Zero
+// disables the check.
+func (lc *LiveDataClient) SetReadDeadline(d time.Duration) {
+	lc.readDeadline = d
+}
+
+// SetWriteDeadline bounds how long GetQuoteCtx will wait, after starting
+// Connect, for the first quote to arrive before giving up and reconnecting.
+// Zero disables the check.
+func (lc *LiveDataClient) SetWriteDeadline(d time.Duration) {
+	lc.writeDeadline = d
+}
+
 func (lc *LiveDataClient) quoteHandler(q stream.Quote) {
 	lc.latestMut.Lock()
 	defer lc.latestMut.Unlock()
 	lc.latest = &q // Keep as pointer to match lc.latest type
+	lc.latestBySymbol[q.Symbol] = &q
+	lc.lastMsg = time.Now()
 }
 
 func (lc *LiveDataClient) GetLatestQuote() (*stream.Quote, bool) {
@@ -173,8 +391,75 @@ func (lc *LiveDataClient) GetLatestQuote() (*stream.Quote, bool) {
 	return &q, true
 }
 
+// GetLatestQuoteFor returns the most recent quote seen for symbol, as
+// opposed to GetLatestQuote's "most recent quote for any subscribed
+// symbol" -- needed by callers (e.g. StartBarAggregation) that must not mix
+// quotes from different symbols on the same shared GetQuote/GetQuoteManaged
+// subscription.
+func (lc *LiveDataClient) GetLatestQuoteFor(symbol string) (*stream.Quote, bool) {
+	lc.latestMut.RLock()
+	defer lc.latestMut.RUnlock()
+	q, ok := lc.latestBySymbol[symbol]
+	if !ok {
+		return nil, false
+	}
+	cp := *q
+	return &cp, true
+}
+
 // GetQuote starts the websocket and subscribes to quotes.
 func (lc *LiveDataClient) GetQuote(symbols []string) {
+	lc.GetQuoteCtx(context.Background(), symbols)
+}
+
+// GetQuoteCtx is the context-aware form of GetQuote. It supervises the
+// websocket for the lifetime of ctx/lc.ctx, auto-reconnecting with
+// exponential backoff (see ReconnectConfig) whenever Connect fails or a
+// SetReadDeadline/SetWriteDeadline watchdog decides the stream has stalled.
+// Health and Done reflect the supervised connection's state.
+func (lc *LiveDataClient) GetQuoteCtx(ctx context.Context, symbols []string) {
+	lc.wg.Add(1)
+	go func() {
+		defer lc.wg.Done()
+		lc.superviseQuotes(ctx, symbols)
+	}()
+}
+
+// superviseQuotes runs connectOnce in a loop, backing off between failed or
+// stale attempts, until ctx or lc.ctx is done.
+func (lc *LiveDataClient) superviseQuotes(ctx context.Context, symbols []string) {
+	for attempt := 0; ; attempt++ {
+		if ctx.Err() != nil || lc.ctx.Err() != nil {
+			return
+		}
+
+		err := lc.connectOnce(ctx, symbols)
+		lc.setHealth(false, err)
+
+		if ctx.Err() != nil || lc.ctx.Err() != nil {
+			return
+		}
+		if lc.reconnect.MaxAttempts > 0 && attempt+1 >= lc.reconnect.MaxAttempts {
+			log.Printf("LiveDataClient: giving up after %d attempts: %v", attempt+1, err)
+			return
+		}
+
+		delay := backoffDelay(lc.reconnect, attempt)
+		log.Printf("LiveDataClient: reconnecting in %s (attempt %d): %v", delay, attempt+1, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		case <-lc.ctx.Done():
+			return
+		}
+	}
+}
+
+// connectOnce opens a single websocket connection, subscribes to symbols,
+// and blocks until it drops (stream error, staleness, or cancellation),
+// returning the reason.
+func (lc *LiveDataClient) connectOnce(ctx context.Context, symbols []string) error {
 	c := stream.NewStocksClient(
 		marketdata.Feed(""), // Empty feed
 		stream.WithCredentials(AlpacaKey, AlpacaSecret),
@@ -182,16 +467,114 @@ func (lc *LiveDataClient) GetQuote(symbols []string) {
 		stream.WithQuotes(lc.quoteHandler, symbols...),
 	)
 
-	lc.client = c
+	lc.setClient(c)
+	connectCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	connectedAt := time.Now()
+	if lc.writeDeadline > 0 {
+		go func() {
+			select {
+			case <-time.After(lc.writeDeadline):
+				lc.latestMut.RLock()
+				connected := lc.lastMsg.After(connectedAt)
+				lc.latestMut.RUnlock()
+				if !connected {
+					log.Printf("Stream did not connect within %s, aborting", lc.writeDeadline)
+					cancel()
+				}
+			case <-connectCtx.Done():
+			}
+		}()
+	}
+
+	if lc.readDeadline > 0 {
+		go func() {
+			ticker := time.NewTicker(lc.readDeadline / 2)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-connectCtx.Done():
+					return
+				case <-ticker.C:
+					lc.latestMut.RLock()
+					stale := !lc.lastMsg.IsZero() && time.Since(lc.lastMsg) > lc.readDeadline
+					lc.latestMut.RUnlock()
+					if stale {
+						log.Printf("No quote received in %s, reconnecting", lc.readDeadline)
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	lc.setHealth(true, nil)
+	// Connect returns once the first connection succeeds or fails; after
+	// that, wait until the stream terminates or connectCtx is cancelled by
+	// one of the watchdogs above.
+	err := c.Connect(connectCtx)
+	if err == nil {
+		select {
+		case err = <-c.Terminated():
+		case <-connectCtx.Done():
+			err = connectCtx.Err()
+		}
+	}
+	_ = c.UnsubscribeFromQuotes(symbols...) // best-effort, unsubscribe cleanly before retrying
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// GetQuoteManaged starts the websocket subscribed to sm's current symbol
+// universe, and re-subscribes/unsubscribes the underlying stream.StocksClient
+// as sm emits SymbolDiffs, so a long-running process doesn't miss
+// newly-listed tickers or keep a subscription open on delisted ones.
+func (lc *LiveDataClient) GetQuoteManaged(ctx context.Context, sm *SymbolManager) {
+	lc.GetQuoteCtx(ctx, sm.Symbols())
+
+	diffCh := make(chan SymbolDiff, 8)
+	sm.Subscribe(diffCh)
 
+	lc.wg.Add(1)
 	go func() {
-		// Connect keeps connection alive / reconnects; blocks until first connect succeeds/fails :contentReference[oaicite:4]{index=4}
-		if err := c.Connect(lc.ctx); err != nil {
-			log.Printf("Stream terminated: %v", err)
+		defer lc.wg.Done()
+		for {
+			select {
+			case <-lc.ctx.Done():
+				return
+			case diff := <-diffCh:
+				c := lc.getClient()
+				if c == nil {
+					continue
+				}
+				if len(diff.Added) > 0 {
+					if err := c.SubscribeToQuotes(lc.quoteHandler, diff.Added...); err != nil {
+						log.Printf("LiveDataClient: subscribe to %v failed: %v", diff.Added, err)
+					}
+				}
+				if len(diff.Removed) > 0 {
+					if err := c.UnsubscribeFromQuotes(diff.Removed...); err != nil {
+						log.Printf("LiveDataClient: unsubscribe from %v failed: %v", diff.Removed, err)
+					}
+				}
+			}
 		}
 	}()
 }
 
+// Stop tears down the stream and signals Done once the handler goroutine
+// spawned by GetQuoteCtx/GetQuoteManaged has fully drained. Safe to call more
+// than once; only the first call has any effect.
 func (lc *LiveDataClient) Stop() {
-	lc.cancel()
+	lc.stopOnce.Do(func() {
+		lc.cancel()
+		go func() {
+			lc.wg.Wait()
+			close(lc.doneCh)
+		}()
+	})
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPageRanges(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC) // 40 days later
+
+	ranges := pageRanges(start, end, 30)
+	if len(ranges) != 2 {
+		t.Fatalf("len(ranges) = %d, want 2", len(ranges))
+	}
+
+	if !ranges[0][0].Equal(start) {
+		t.Fatalf("ranges[0] start = %v, want %v", ranges[0][0], start)
+	}
+	wantFirstEnd := start.AddDate(0, 0, 30)
+	if !ranges[0][1].Equal(wantFirstEnd) {
+		t.Fatalf("ranges[0] end = %v, want %v", ranges[0][1], wantFirstEnd)
+	}
+
+	if !ranges[1][0].Equal(wantFirstEnd) {
+		t.Fatalf("ranges[1] start = %v, want %v", ranges[1][0], wantFirstEnd)
+	}
+	// The last page is clipped to end, not a full 30-day window.
+	if !ranges[1][1].Equal(end) {
+		t.Fatalf("ranges[1] end = %v, want %v (clipped)", ranges[1][1], end)
+	}
+}
+
+func TestPageRangesEmptyWhenStartNotBeforeEnd(t *testing.T) {
+	same := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if ranges := pageRanges(same, same, 30); len(ranges) != 0 {
+		t.Fatalf("pageRanges(same, same, 30) = %v, want empty", ranges)
+	}
+}
+
+func TestGetHistoryCtxRespectsCancellation(t *testing.T) {
+	sc := NewStockClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	end := time.Date(2025, 1, 15, 21, 0, 0, 0, time.UTC)
+	_, err := sc.GetHistoryCtx(ctx, []string{"AAPL"}, 5, &end, "minute")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetHistoryCtx err = %v, want context.Canceled", err)
+	}
+}
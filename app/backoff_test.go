@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelayDoublesAndCaps(t *testing.T) {
+	cfg := ReconnectConfig{InitialDelay: time.Second, MaxDelay: 8 * time.Second}
+
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{3, 8 * time.Second},  // would be 8s uncapped
+		{4, 8 * time.Second},  // would be 16s, capped
+		{10, 8 * time.Second}, // stays capped
+	}
+	for _, c := range cases {
+		if got := backoffDelay(cfg, c.attempt); got != c.want {
+			t.Errorf("backoffDelay(attempt=%d) = %v, want %v", c.attempt, got, c.want)
+		}
+	}
+}
+
+func TestBackoffDelayJitterStaysInBounds(t *testing.T) {
+	cfg := ReconnectConfig{InitialDelay: time.Second, MaxDelay: 30 * time.Second, Jitter: 0.2}
+
+	for i := 0; i < 100; i++ {
+		d := backoffDelay(cfg, 2) // base 4s +/- 20%
+		if d < 0 {
+			t.Fatalf("backoffDelay returned negative duration: %v", d)
+		}
+		min := 4*time.Second - 4*time.Second*2/10
+		max := 4*time.Second + 4*time.Second*2/10
+		if d < min || d > max {
+			t.Fatalf("backoffDelay(attempt=2) = %v, want in [%v, %v]", d, min, max)
+		}
+	}
+}
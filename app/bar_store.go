@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+)
+
+// BarStore persists fetched bar series to local per-symbol/per-year files so
+// strategies can run against a unified historical+live series without
+// re-fetching from Alpaca on every run. It's a simple gob-encoded append
+// format rather than Parquet, keeping it dependency-free like the rest of
+// this package.
+//
+// Layout: <dir>/<symbol>/<timeframe>/<year>.gob, one file per
+// (symbol, timeframe, year) tuple, inspired by the alpacabkfeeder pattern of
+// partitioning bar storage by year.
+type BarStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewBarStore creates a BarStore rooted at dir, creating the directory if
+// it doesn't already exist.
+func NewBarStore(dir string) (*BarStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("bar store: %w", err)
+	}
+	return &BarStore{dir: dir}, nil
+}
+
+func (bs *BarStore) path(symbol, timeframe string, year int) string {
+	return filepath.Join(bs.dir, symbol, timeframe, fmt.Sprintf("%d.gob", year))
+}
+
+// Write appends bars for symbol/timeframe to the on-disk store, splitting
+// them across the per-year files they fall in.
+func (bs *BarStore) Write(symbol, timeframe string, bars []marketdata.Bar) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	byYear := make(map[int][]marketdata.Bar)
+	for _, b := range bars {
+		byYear[b.Timestamp.UTC().Year()] = append(byYear[b.Timestamp.UTC().Year()], b)
+	}
+
+	for year, yearBars := range byYear {
+		p := bs.path(symbol, timeframe, year)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		w := bufio.NewWriter(f)
+		enc := gob.NewEncoder(w)
+		for _, b := range yearBars {
+			if err := enc.Encode(b); err != nil {
+				f.Close()
+				return err
+			}
+		}
+		if err := w.Flush(); err != nil {
+			f.Close()
+			return err
+		}
+		f.Close()
+	}
+	return nil
+}
+
+// Read returns bars for symbol/timeframe between start and end (inclusive),
+// read from whichever per-year files overlap the range and de-duplicated/
+// sorted by timestamp.
+func (bs *BarStore) Read(symbol, timeframe string, start, end time.Time) ([]marketdata.Bar, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	var out []marketdata.Bar
+	for year := start.UTC().Year(); year <= end.UTC().Year(); year++ {
+		p := bs.path(symbol, timeframe, year)
+		f, err := os.Open(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		dec := gob.NewDecoder(bufio.NewReader(f))
+		for {
+			var b marketdata.Bar
+			if err := dec.Decode(&b); err != nil {
+				// Each Write appends a fresh gob stream that re-sends its
+				// type definitions; skip those and keep reading its bars.
+				if strings.Contains(err.Error(), "duplicate type received") {
+					continue
+				}
+				break
+			}
+			ts := b.Timestamp.UTC()
+			if !ts.Before(start) && !ts.After(end) {
+				out = append(out, b)
+			}
+		}
+		f.Close()
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Timestamp.Before(out[j].Timestamp) })
+	return dedupeBars(out), nil
+}
+
+func dedupeBars(bars []marketdata.Bar) []marketdata.Bar {
+	out := bars[:0]
+	var last time.Time
+	for i, b := range bars {
+		if i > 0 && b.Timestamp.Equal(last) {
+			continue
+		}
+		out = append(out, b)
+		last = b.Timestamp
+	}
+	return out
+}
+
+// Backfill fills gaps in the store for symbols/timeframe over [from, to] by
+// paging through StockClient.GetHistory and writing whatever isn't already
+// on disk. It walks month by month so a single Backfill call doesn't try to
+// pull years of minute bars in one request. Each page's fetched bars are
+// diffed against what Read already returns for that page before writing, so
+// re-running Backfill over an already-filled range doesn't duplicate rows.
+func (bs *BarStore) Backfill(sc *StockClient, symbols []string, timeframe string, from, to time.Time) error {
+	for cursor := from; cursor.Before(to); cursor = cursor.AddDate(0, 1, 0) {
+		pageEnd := cursor.AddDate(0, 1, 0)
+		if pageEnd.After(to) {
+			pageEnd = to
+		}
+
+		// GetHistory's lookback counts trading days, not calendar days.
+		lookback := sc.calendar.TradingDaysBetween(cursor, pageEnd)
+		if lookback == 0 {
+			continue
+		}
+
+		bars, err := sc.GetHistory(symbols, lookback, &pageEnd, timeframe)
+		if err != nil {
+			return fmt.Errorf("bar store backfill %s: %w", strings.Join(symbols, ","), err)
+		}
+		for symbol, symbolBars := range bars {
+			if len(symbolBars) == 0 {
+				continue
+			}
+			// GetHistory's window can start before cursor, so compare
+			// against what's stored over the fetched bars' own span.
+			existing, err := bs.Read(symbol, timeframe, symbolBars[0].Timestamp, symbolBars[len(symbolBars)-1].Timestamp)
+			if err != nil {
+				return err
+			}
+			missing := missingBars(existing, symbolBars)
+			if len(missing) == 0 {
+				continue
+			}
+			if err := bs.Write(symbol, timeframe, missing); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// missingBars returns the bars in fetched whose timestamp isn't already
+// covered by existing, split out of Backfill so the dedup-against-disk
+// logic is testable without a network round trip.
+func missingBars(existing, fetched []marketdata.Bar) []marketdata.Bar {
+	have := make(map[int64]bool, len(existing))
+	for _, b := range existing {
+		have[b.Timestamp.UTC().Unix()] = true
+	}
+
+	missing := fetched[:0]
+	for _, b := range fetched {
+		if !have[b.Timestamp.UTC().Unix()] {
+			missing = append(missing, b)
+		}
+	}
+	return missing
+}
+
+// barAggregator folds incoming quotes into a running 1-minute bar, flushing
+// completed bars to barsCh as each minute boundary rolls over.
+type barAggregator struct {
+	mu      sync.Mutex
+	current *marketdata.Bar
+	minute  time.Time
+	barsCh  chan<- marketdata.Bar
+}
+
+func newBarAggregator(barsCh chan<- marketdata.Bar) *barAggregator {
+	return &barAggregator{barsCh: barsCh}
+}
+
+// addQuote folds a quote's midpoint price into the bar for its minute,
+// flushing the previous minute's bar if this quote starts a new one.
+func (ba *barAggregator) addQuote(symbol string, price float64, ts time.Time) {
+	ba.mu.Lock()
+	defer ba.mu.Unlock()
+
+	minute := ts.UTC().Truncate(time.Minute)
+	if ba.current == nil || !minute.Equal(ba.minute) {
+		if ba.current != nil {
+			ba.barsCh <- *ba.current
+		}
+		ba.minute = minute
+		ba.current = &marketdata.Bar{
+			Timestamp: minute,
+			Open:      price,
+			High:      price,
+			Low:       price,
+			Close:     price,
+		}
+		return
+	}
+
+	if price > ba.current.High {
+		ba.current.High = price
+	}
+	if price < ba.current.Low {
+		ba.current.Low = price
+	}
+	ba.current.Close = price
+}
+
+// flushIfStale flushes the in-progress bar if the wall clock has moved past
+// its minute, even without a new quote arriving to trigger addQuote's flush.
+// Without this, the final bar of any quiet period (market close, feed
+// stall) would sit in ba.current forever, since addQuote only flushes on
+// seeing a later quote timestamp.
+func (ba *barAggregator) flushIfStale(now time.Time) {
+	ba.mu.Lock()
+	defer ba.mu.Unlock()
+
+	if ba.current == nil || now.UTC().Truncate(time.Minute).Equal(ba.minute) {
+		return
+	}
+	ba.barsCh <- *ba.current
+	ba.current = nil
+}
+
+// StartBarAggregation registers a background worker on lc that aggregates
+// incoming quotes for symbol into 1-minute bars and appends them to store,
+// so callers get a unified historical+live series without re-fetching from
+// Alpaca. It returns a stop func that unregisters the worker.
+func (lc *LiveDataClient) StartBarAggregation(store *BarStore, symbol string) (stop func()) {
+	barsCh := make(chan marketdata.Bar, 64)
+	agg := newBarAggregator(barsCh)
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		// lastTS keeps the same latest quote from being folded in again on
+		// every tick, which would re-open an already flushed minute.
+		var lastTS time.Time
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if q, ok := lc.GetLatestQuoteFor(symbol); ok && q.Timestamp.After(lastTS) {
+					lastTS = q.Timestamp
+					mid := (float64(q.BidPrice) + float64(q.AskPrice)) / 2
+					agg.addQuote(symbol, mid, q.Timestamp)
+				}
+				agg.flushIfStale(time.Now())
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case b := <-barsCh:
+				if err := store.Write(symbol, "minute", []marketdata.Bar{b}); err != nil {
+					log.Printf("bar aggregation: write failed for %s: %v", symbol, err)
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
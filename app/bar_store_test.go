@@ -0,0 +1,171 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata/stream"
+)
+
+func TestBarStoreWriteReadRoundTrip(t *testing.T) {
+	bs, err := NewBarStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewBarStore: %v", err)
+	}
+
+	bars := []marketdata.Bar{
+		{Timestamp: time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC), Close: 100},
+		{Timestamp: time.Date(2024, 3, 1, 14, 31, 0, 0, time.UTC), Close: 101},
+		{Timestamp: time.Date(2025, 1, 2, 14, 30, 0, 0, time.UTC), Close: 200}, // different year file
+	}
+	if err := bs.Write("AAPL", "minute", bars); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	got, err := bs.Read("AAPL", "minute",
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(got) != len(bars) {
+		t.Fatalf("Read returned %d bars, want %d", len(got), len(bars))
+	}
+	for i, b := range got {
+		if !b.Timestamp.Equal(bars[i].Timestamp) || b.Close != bars[i].Close {
+			t.Errorf("bar %d = %+v, want %+v", i, b, bars[i])
+		}
+	}
+}
+
+func TestBarStoreReadFiltersRangeAndSymbol(t *testing.T) {
+	bs, err := NewBarStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewBarStore: %v", err)
+	}
+
+	if err := bs.Write("AAPL", "minute", []marketdata.Bar{
+		{Timestamp: time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)},
+		{Timestamp: time.Date(2024, 3, 2, 14, 30, 0, 0, time.UTC)},
+	}); err != nil {
+		t.Fatalf("Write AAPL: %v", err)
+	}
+	if err := bs.Write("MSFT", "minute", []marketdata.Bar{
+		{Timestamp: time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)},
+	}); err != nil {
+		t.Fatalf("Write MSFT: %v", err)
+	}
+
+	got, err := bs.Read("AAPL", "minute",
+		time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 23, 59, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Read returned %d bars, want 1 (AAPL, 2024-03-01 only)", len(got))
+	}
+}
+
+func TestDedupeBars(t *testing.T) {
+	in := []marketdata.Bar{
+		{Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Close: 1},
+		{Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Close: 2}, // dup timestamp, dropped
+		{Timestamp: time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC), Close: 3},
+	}
+	out := dedupeBars(in)
+	if len(out) != 2 {
+		t.Fatalf("dedupeBars returned %d bars, want 2", len(out))
+	}
+	if out[0].Close != 1 || out[1].Close != 3 {
+		t.Fatalf("dedupeBars = %+v, want first occurrence of each timestamp kept", out)
+	}
+}
+
+func TestMissingBars(t *testing.T) {
+	existing := []marketdata.Bar{
+		{Timestamp: time.Date(2024, 1, 1, 14, 30, 0, 0, time.UTC)},
+		{Timestamp: time.Date(2024, 1, 1, 14, 31, 0, 0, time.UTC)},
+	}
+	fetched := []marketdata.Bar{
+		{Timestamp: time.Date(2024, 1, 1, 14, 30, 0, 0, time.UTC)}, // already on disk
+		{Timestamp: time.Date(2024, 1, 1, 14, 32, 0, 0, time.UTC)}, // new
+	}
+
+	got := missingBars(existing, fetched)
+	if len(got) != 1 {
+		t.Fatalf("missingBars returned %d bars, want 1", len(got))
+	}
+	want := time.Date(2024, 1, 1, 14, 32, 0, 0, time.UTC)
+	if !got[0].Timestamp.Equal(want) {
+		t.Fatalf("missingBars[0].Timestamp = %v, want %v", got[0].Timestamp, want)
+	}
+}
+
+func TestMissingBarsAllNew(t *testing.T) {
+	fetched := []marketdata.Bar{
+		{Timestamp: time.Date(2024, 1, 1, 14, 30, 0, 0, time.UTC)},
+		{Timestamp: time.Date(2024, 1, 1, 14, 31, 0, 0, time.UTC)},
+	}
+	got := missingBars(nil, fetched)
+	if len(got) != len(fetched) {
+		t.Fatalf("missingBars(nil, fetched) returned %d bars, want %d", len(got), len(fetched))
+	}
+}
+
+// TestBarAggregatorFlushesStaleBarOnWallClock guards against the bug where a
+// quiet feed (no new quote, so no new minute on the quote timestamp) left
+// the final in-progress bar stuck in ba.current forever.
+func TestBarAggregatorFlushesStaleBarOnWallClock(t *testing.T) {
+	barsCh := make(chan marketdata.Bar, 1)
+	agg := newBarAggregator(barsCh)
+
+	quoteTime := time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)
+	agg.addQuote("AAPL", 100, quoteTime)
+
+	// No new quote arrives, but wall clock has moved into the next minute.
+	agg.flushIfStale(quoteTime.Add(time.Minute))
+
+	select {
+	case b := <-barsCh:
+		if !b.Timestamp.Equal(quoteTime.Truncate(time.Minute)) {
+			t.Fatalf("flushed bar timestamp = %v, want %v", b.Timestamp, quoteTime.Truncate(time.Minute))
+		}
+	default:
+		t.Fatalf("flushIfStale did not flush the stale in-progress bar")
+	}
+
+	// A second call with no new current bar must not flush again.
+	agg.flushIfStale(quoteTime.Add(2 * time.Minute))
+	select {
+	case b := <-barsCh:
+		t.Fatalf("flushIfStale flushed again with no pending bar: %+v", b)
+	default:
+	}
+}
+
+// TestGetLatestQuoteForDoesNotCrossContaminate guards against the bug where
+// StartBarAggregation used GetLatestQuote (the most recent quote for *any*
+// symbol on a shared GetQuote/GetQuoteManaged subscription) instead of the
+// quote for its own symbol.
+func TestGetLatestQuoteForDoesNotCrossContaminate(t *testing.T) {
+	lc := NewLiveDataClient()
+	defer lc.Stop()
+
+	lc.quoteHandler(stream.Quote{Symbol: "AAPL", BidPrice: 100, AskPrice: 100})
+	lc.quoteHandler(stream.Quote{Symbol: "MSFT", BidPrice: 400, AskPrice: 400})
+
+	aapl, ok := lc.GetLatestQuoteFor("AAPL")
+	if !ok || aapl.BidPrice != 100 {
+		t.Fatalf("GetLatestQuoteFor(AAPL) = %+v, %v, want BidPrice=100", aapl, ok)
+	}
+	msft, ok := lc.GetLatestQuoteFor("MSFT")
+	if !ok || msft.BidPrice != 400 {
+		t.Fatalf("GetLatestQuoteFor(MSFT) = %+v, %v, want BidPrice=400", msft, ok)
+	}
+
+	if _, ok := lc.GetLatestQuoteFor("GOOG"); ok {
+		t.Fatalf("GetLatestQuoteFor(GOOG) = ok, want no quote seen for an unsubscribed symbol")
+	}
+}
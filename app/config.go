@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -10,6 +11,15 @@ import (
 var (
 	AlpacaKey    string
 	AlpacaSecret string
+
+	// AssetExchanges restricts SymbolManager to symbols listed on these
+	// exchanges (e.g. NASDAQ, NYSE, NYSEARCA, OTC). Empty means no filter.
+	AssetExchanges []string
+	// RequireTradable, RequireFractionable, and RequireShortable filter the
+	// SymbolManager universe to assets with the matching Alpaca asset flags.
+	RequireTradable     bool
+	RequireFractionable bool
+	RequireShortable    bool
 )
 
 func LoadConfig() {
@@ -25,4 +35,17 @@ func LoadConfig() {
 	if AlpacaKey == "" || AlpacaSecret == "" {
 		log.Println("Warning: ALPACA_KEY or ALPACA_SECRET is missing")
 	}
+
+	AssetExchanges = nil
+	if raw := os.Getenv("ASSET_EXCHANGES"); raw != "" {
+		for _, ex := range strings.Split(raw, ",") {
+			if ex = strings.ToUpper(strings.TrimSpace(ex)); ex != "" {
+				AssetExchanges = append(AssetExchanges, ex)
+			}
+		}
+	}
+
+	RequireTradable = os.Getenv("REQUIRE_TRADABLE") != "false"
+	RequireFractionable = os.Getenv("REQUIRE_FRACTIONABLE") == "true"
+	RequireShortable = os.Getenv("REQUIRE_SHORTABLE") == "true"
 }
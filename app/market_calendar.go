@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// holidaySession describes a single non-standard trading day: either the
+// market is fully closed, or it closes early (half day, 1:00 PM ET).
+type holidaySession struct {
+	closed  bool
+	close13 bool // closes at 13:00 ET instead of 16:00 ET
+}
+
+// newYorkLocation is the IANA zone NYSE session bounds are computed in, so
+// SessionBounds reflects real ET (EST/EDT) rather than a fixed UTC offset.
+// Loaded once at startup; falls back to UTC (making SessionBounds wrong by
+// the DST offset) only if the platform has no tzdata, which would also
+// break every other ET-aware consumer in the process.
+var newYorkLocation = func() *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		log.Printf("MarketCalendar: failed to load America/New_York zone, falling back to UTC: %v", err)
+		return time.UTC
+	}
+	return loc
+}()
+
+// MarketCalendar knows which days the NYSE/Nasdaq are open and what the
+// session bounds are, replacing the hardcoded 13:30-20:00 UTC window that
+// UsTradingHours used to return for every day.
+//
+// It is seeded from a static table of known holidays/half-days and can
+// optionally be refreshed from Alpaca's /v2/calendar endpoint, which is
+// authoritative (it accounts for ad-hoc closures the static table can't
+// predict).
+type MarketCalendar struct {
+	holidays map[string]holidaySession // key: "2006-01-02"
+}
+
+// NewMarketCalendar builds a calendar backed by the embedded static holiday
+// table. Call LoadFromAlpaca afterwards to refresh it from the live
+// /v2/calendar endpoint if a more accurate source is desired.
+func NewMarketCalendar() *MarketCalendar {
+	mc := &MarketCalendar{holidays: make(map[string]holidaySession)}
+	for k, v := range staticHolidays {
+		mc.holidays[k] = v
+	}
+	return mc
+}
+
+// staticHolidays is the embedded NYSE holiday table (full closures and
+// 1:00 PM ET half days), covering observed-Saturday/Sunday shifts. It is
+// not exhaustive forever -- LoadFromAlpaca should be used for long-running
+// processes that span years not listed here.
+var staticHolidays = map[string]holidaySession{
+	// 2024
+	"2024-01-01": {closed: true}, // New Year's Day
+	"2024-01-15": {closed: true}, // MLK Day
+	"2024-02-19": {closed: true}, // Washington's Birthday
+	"2024-03-29": {closed: true}, // Good Friday
+	"2024-05-27": {closed: true}, // Memorial Day
+	"2024-06-19": {closed: true}, // Juneteenth
+	"2024-07-03": {close13: true},
+	"2024-07-04": {closed: true}, // Independence Day
+	"2024-09-02": {closed: true}, // Labor Day
+	"2024-11-28": {closed: true}, // Thanksgiving
+	"2024-11-29": {close13: true},
+	"2024-12-24": {close13: true},
+	"2024-12-25": {closed: true}, // Christmas
+	// 2025
+	"2025-01-01": {closed: true},
+	"2025-01-09": {closed: true}, // National Day of Mourning (Carter)
+	"2025-01-20": {closed: true}, // MLK Day
+	"2025-02-17": {closed: true}, // Washington's Birthday
+	"2025-04-18": {closed: true}, // Good Friday
+	"2025-05-26": {closed: true}, // Memorial Day
+	"2025-06-19": {closed: true}, // Juneteenth
+	"2025-07-03": {close13: true},
+	"2025-07-04": {closed: true},
+	"2025-09-01": {closed: true}, // Labor Day
+	"2025-11-27": {closed: true}, // Thanksgiving
+	"2025-11-28": {close13: true},
+	"2025-12-24": {close13: true},
+	"2025-12-25": {closed: true},
+	// 2026
+	"2026-01-01": {closed: true},
+	"2026-01-19": {closed: true}, // MLK Day
+	"2026-02-16": {closed: true}, // Washington's Birthday
+	"2026-04-03": {closed: true}, // Good Friday
+	"2026-05-25": {closed: true}, // Memorial Day
+	"2026-06-19": {closed: true}, // Juneteenth
+	"2026-07-03": {closed: true}, // Independence Day observed (Jul 4 is Saturday)
+	"2026-09-07": {closed: true}, // Labor Day
+	"2026-11-26": {closed: true}, // Thanksgiving
+	"2026-11-27": {close13: true},
+	"2026-12-24": {close13: true},
+	"2026-12-25": {closed: true},
+}
+
+// alpacaCalendarDay mirrors the shape of a single entry in Alpaca's
+// /v2/calendar response.
+type alpacaCalendarDay struct {
+	Date          string `json:"date"`
+	Open          string `json:"open"`
+	Close         string `json:"close"`
+	SettlementDate string `json:"settlement_date"`
+}
+
+// LoadFromAlpaca refreshes the calendar from Alpaca's /v2/calendar endpoint
+// for the given inclusive date range, overwriting any overlapping static
+// entries with the authoritative ones. It is best-effort: a failure here
+// just means the static table keeps being used.
+func (mc *MarketCalendar) LoadFromAlpaca(start, end time.Time) error {
+	url := fmt.Sprintf("https://api.alpaca.markets/v2/calendar?start=%s&end=%s",
+		start.Format("2006-01-02"), end.Format("2006-01-02"))
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("APCA-API-KEY-ID", AlpacaKey)
+	req.Header.Set("APCA-API-SECRET-KEY", AlpacaSecret)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("alpaca calendar request failed: %s", resp.Status)
+	}
+
+	var days []alpacaCalendarDay
+	if err := json.NewDecoder(resp.Body).Decode(&days); err != nil {
+		return err
+	}
+
+	open := make(map[string]bool, len(days))
+	for _, d := range days {
+		open[d.Date] = true
+		if d.Close == "13:00" {
+			mc.holidays[d.Date] = holidaySession{close13: true}
+		} else {
+			delete(mc.holidays, d.Date)
+		}
+	}
+
+	// Any weekday in range that Alpaca didn't return a session for is closed.
+	for t := start; !t.After(end); t = t.AddDate(0, 0, 1) {
+		if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+			continue
+		}
+		key := t.Format("2006-01-02")
+		if !open[key] {
+			mc.holidays[key] = holidaySession{closed: true}
+		}
+	}
+
+	log.Printf("MarketCalendar: refreshed %d-%d from Alpaca /v2/calendar", start.Year(), end.Year())
+	return nil
+}
+
+// IsOpen reports whether the market trades at all on day (weekday and not
+// a full closure).
+func (mc *MarketCalendar) IsOpen(day time.Time) bool {
+	day = day.UTC()
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false
+	}
+	return !mc.holidays[day.Format("2006-01-02")].closed
+}
+
+// SessionBounds returns the UTC open/close for day and whether it's a
+// 1:00 PM ET half day. If the market is closed on day, open and close are
+// both the zero time.
+func (mc *MarketCalendar) SessionBounds(day time.Time) (open, close time.Time, isHalfDay bool) {
+	day = day.UTC()
+	if !mc.IsOpen(day) {
+		return time.Time{}, time.Time{}, false
+	}
+
+	h := mc.holidays[day.Format("2006-01-02")]
+	open = time.Date(day.Year(), day.Month(), day.Day(), 9, 30, 0, 0, newYorkLocation).UTC()
+	if h.close13 {
+		close = time.Date(day.Year(), day.Month(), day.Day(), 13, 0, 0, 0, newYorkLocation).UTC()
+		return open, close, true
+	}
+	close = time.Date(day.Year(), day.Month(), day.Day(), 16, 0, 0, 0, newYorkLocation).UTC()
+	return open, close, false
+}
+
+// PreviousTradingDay returns the most recent open trading day strictly
+// before day (by calendar date, not by session timestamp). Unlike walking
+// PreviousClose off a shifted timestamp, this can't be thrown off by a half
+// day's early close landing before the previous regular day's close on the
+// clock -- it only ever compares dates.
+func (mc *MarketCalendar) PreviousTradingDay(day time.Time) time.Time {
+	day = day.UTC()
+	for i := 0; i < 14; i++ {
+		day = day.AddDate(0, 0, -1)
+		if mc.IsOpen(day) {
+			return day
+		}
+	}
+	return time.Time{}
+}
+
+// NextOpen returns the open of the next session at or after t. If t falls
+// inside a session that has already opened, that session's open (which is
+// before t) is returned.
+func (mc *MarketCalendar) NextOpen(t time.Time) time.Time {
+	day := t.UTC()
+	for i := 0; i < 14; i++ {
+		if open, _, _ := mc.SessionBounds(day); !open.IsZero() && !open.Before(t) {
+			return open
+		}
+		if open, close, _ := mc.SessionBounds(day); !open.IsZero() && t.Before(close) {
+			// t falls inside today's (already started) session.
+			return open
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+// TradingDaysBetween counts the open trading days in [start, end]
+// (inclusive), for callers that need to convert a calendar-day span (e.g. a
+// backfill page) into the trading-day count GetHistory's lookback expects.
+func (mc *MarketCalendar) TradingDaysBetween(start, end time.Time) int {
+	count := 0
+	for d := start.UTC(); !d.After(end.UTC()); d = d.AddDate(0, 0, 1) {
+		if mc.IsOpen(d) {
+			count++
+		}
+	}
+	return count
+}
+
+// PreviousClose returns the close of the most recent session at or before t.
+func (mc *MarketCalendar) PreviousClose(t time.Time) time.Time {
+	day := t.UTC()
+	for i := 0; i < 14; i++ {
+		if _, close, _ := mc.SessionBounds(day); !close.IsZero() && !close.After(t) {
+			return close
+		}
+		day = day.AddDate(0, 0, -1)
+	}
+	return time.Time{}
+}
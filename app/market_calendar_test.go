@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionBoundsAcrossDST(t *testing.T) {
+	mc := NewMarketCalendar()
+
+	// 2025-01-15 is EST (UTC-5): regular session is 14:30-21:00 UTC.
+	open, close, half := mc.SessionBounds(time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC))
+	if half {
+		t.Fatalf("2025-01-15 should not be a half day")
+	}
+	wantOpen := time.Date(2025, 1, 15, 14, 30, 0, 0, time.UTC)
+	wantClose := time.Date(2025, 1, 15, 21, 0, 0, 0, time.UTC)
+	if !open.Equal(wantOpen) || !close.Equal(wantClose) {
+		t.Fatalf("EST session bounds = %v-%v, want %v-%v", open, close, wantOpen, wantClose)
+	}
+
+	// 2025-07-03 is EDT (UTC-4) and a half day: 13:30-17:00 UTC.
+	open, close, half = mc.SessionBounds(time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC))
+	if !half {
+		t.Fatalf("2025-07-03 should be a half day")
+	}
+	wantOpen = time.Date(2025, 7, 3, 13, 30, 0, 0, time.UTC)
+	wantClose = time.Date(2025, 7, 3, 17, 0, 0, 0, time.UTC)
+	if !open.Equal(wantOpen) || !close.Equal(wantClose) {
+		t.Fatalf("EDT half day bounds = %v-%v, want %v-%v", open, close, wantOpen, wantClose)
+	}
+}
+
+func TestPreviousTradingDaySkipsHalfDayCorrectly(t *testing.T) {
+	mc := NewMarketCalendar()
+
+	// 2024-12-24 is a half day; 2024-12-25 is closed; the trading day
+	// immediately before 2024-12-24 is 2024-12-23, a regular session.
+	prev := mc.PreviousTradingDay(time.Date(2024, 12, 24, 18, 0, 0, 0, time.UTC))
+	if prev.Format("2006-01-02") != "2024-12-23" {
+		t.Fatalf("PreviousTradingDay(2024-12-24) = %s, want 2024-12-23", prev.Format("2006-01-02"))
+	}
+}
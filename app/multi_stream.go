@@ -0,0 +1,519 @@
+package main
+
+import (
+	"log"
+	"sync"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata/stream"
+)
+
+// assetClass identifies which Alpaca feed a multi-stream subscription
+// belongs to.
+type assetClass string
+
+const (
+	AssetStocks  assetClass = "stocks"
+	AssetOptions assetClass = "options"
+	AssetCrypto  assetClass = "crypto"
+	AssetNews    assetClass = "news"
+)
+
+// msgType identifies the kind of message a multi-stream subscription
+// carries.
+type msgType string
+
+const (
+	MsgTrade      msgType = "trade"
+	MsgQuote      msgType = "quote"
+	MsgBar        msgType = "bar"
+	MsgUpdatedBar msgType = "updated_bar"
+	MsgStatus     msgType = "status"
+	MsgLULD       msgType = "luld"
+	MsgNews       msgType = "news"
+)
+
+// snapshotKey is the (assetClass, symbol, msgType) tuple snapshots and
+// subscriptions are keyed by.
+type snapshotKey struct {
+	asset  assetClass
+	symbol string
+	msg    msgType
+}
+
+// ringBuffer is a fixed-size queue fed by the Alpaca handler goroutine and
+// drained by a subscription's dispatcher goroutine. It never blocks the
+// producer waiting on a slow subscriber -- it just overwrites the oldest
+// unread entry once the buffer is full. push/pop share a slot in exactly
+// that overflow case (the producer's next write and the consumer's oldest
+// read land on the same index), so buf/head/tail are all guarded by mu
+// rather than left to atomics, which would only protect the counters and
+// not the slot data itself.
+type ringBuffer[T any] struct {
+	mu   sync.Mutex
+	buf  []T
+	mask uint64
+	head uint64
+	tail uint64
+}
+
+// newRingBuffer creates a ring buffer of the given capacity, which is
+// rounded up to the next power of two.
+func newRingBuffer[T any](capacity int) *ringBuffer[T] {
+	size := 1
+	for size < capacity {
+		size <<= 1
+	}
+	return &ringBuffer[T]{buf: make([]T, size), mask: uint64(size - 1)}
+}
+
+// push writes v, dropping the oldest unread entry if the buffer is full.
+func (r *ringBuffer[T]) push(v T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf[r.head&r.mask] = v
+	r.head++
+	if r.head-r.tail > uint64(len(r.buf)) {
+		r.tail++
+	}
+}
+
+// pop reads the oldest unread entry, if any.
+func (r *ringBuffer[T]) pop() (T, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tail == r.head {
+		var zero T
+		return zero, false
+	}
+	v := r.buf[r.tail&r.mask]
+	r.tail++
+	return v, true
+}
+
+// multiSub is one (assetClass, symbol, msgType) subscription: a ring
+// buffer fed by the Alpaca handler goroutine, drained by a dedicated
+// dispatcher goroutine that fans each message out to every registered
+// handler func.
+type multiSub struct {
+	rb   *ringBuffer[any]
+	wake chan struct{}
+	stop chan struct{}
+
+	mu       sync.Mutex
+	handlers map[int]func(any)
+	nextID   int
+}
+
+// addHandler registers fn (already wrapped as func(any)) and returns its ID
+// for later removal.
+func (s *multiSub) addHandler(fn func(any)) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID
+	s.nextID++
+	s.handlers[id] = fn
+	return id
+}
+
+// removeHandler drops the handler with id and reports whether the
+// subscription has no handlers left.
+func (s *multiSub) removeHandler(id int) (empty bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.handlers, id)
+	return len(s.handlers) == 0
+}
+
+func (s *multiSub) dispatch(v any) {
+	s.mu.Lock()
+	fns := make([]func(any), 0, len(s.handlers))
+	for _, fn := range s.handlers {
+		fns = append(fns, fn)
+	}
+	s.mu.Unlock()
+	for _, fn := range fns {
+		fn(v)
+	}
+}
+
+func (s *multiSub) run(done <-chan struct{}) {
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-done:
+			return
+		case <-s.wake:
+		}
+		for {
+			v, ok := s.rb.pop()
+			if !ok {
+				break
+			}
+			s.dispatch(v)
+		}
+	}
+}
+
+// multiStreamState holds everything OnStockQuote/OnCryptoTrade/etc. need:
+// the lazily-created per-asset-class stream clients (one websocket each,
+// shared across every symbol/msgType subscribed on that asset class) and
+// the subscription/snapshot bookkeeping. It lives on LiveDataClient so a
+// single process has one place to look for "what have I seen recently".
+//
+// Unlike the GetQuote/GetQuoteManaged quote path, these four
+// clients are NOT wired into the reconnect/backoff/Health() machinery in
+// alpaca_loader.go -- ensure*MultiClient connects once and, if that connection
+// ever terminates (backoffDelay, ReconnectConfig, superviseQuotes don't
+// apply here), the asset class stays dead for the rest of the process with
+// only a log line to show for it. Reconnecting here would mean recreating
+// the client (the SDK's stream clients aren't reusable once terminated)
+// and replaying every subscribeUnderlying call recorded in st.subs, which
+// subscribeMulti doesn't currently support. Until that's built, treat
+// multi-stream subscriptions (stocks, options, crypto, news) as best-effort
+// for the lifetime of one websocket connection, not as resilient as the
+// quote path.
+type multiStreamState struct {
+	mu sync.Mutex
+
+	stocksClient  *stream.StocksClient
+	optionsClient *stream.OptionClient
+	cryptoClient  *stream.CryptoClient
+	newsClient    *stream.NewsClient
+
+	subs map[snapshotKey]*multiSub
+
+	snapshotMu sync.RWMutex
+	snapshots  map[snapshotKey]any
+}
+
+func newMultiStreamState() *multiStreamState {
+	return &multiStreamState{
+		subs:      make(map[snapshotKey]*multiSub),
+		snapshots: make(map[snapshotKey]any),
+	}
+}
+
+// Snapshot returns the latest message seen for each msgType of
+// (asset, symbol), e.g. Snapshot(AssetStocks, "AAPL")[MsgQuote] is the most
+// recent stream.Quote received for AAPL.
+func (lc *LiveDataClient) Snapshot(asset assetClass, symbol string) map[msgType]any {
+	st := lc.multiStream()
+	st.snapshotMu.RLock()
+	defer st.snapshotMu.RUnlock()
+	out := make(map[msgType]any)
+	for k, v := range st.snapshots {
+		if k.asset == asset && k.symbol == symbol {
+			out[k.msg] = v
+		}
+	}
+	return out
+}
+
+func (lc *LiveDataClient) multiStream() *multiStreamState {
+	lc.multiOnce.Do(func() { lc.multi = newMultiStreamState() })
+	return lc.multi
+}
+
+// onMultiMessage is the entry point every Alpaca handler (OnStockQuote's
+// underlying callback, etc.) funnels into: it records the snapshot and
+// pushes the message onto that subscription's ring buffer without ever
+// blocking on a subscriber.
+func (lc *LiveDataClient) onMultiMessage(key snapshotKey, v any) {
+	st := lc.multiStream()
+
+	st.snapshotMu.Lock()
+	st.snapshots[key] = v
+	st.snapshotMu.Unlock()
+
+	st.mu.Lock()
+	sub := st.subs[key]
+	st.mu.Unlock()
+	if sub == nil {
+		return
+	}
+	sub.rb.push(v)
+	select {
+	case sub.wake <- struct{}{}:
+	default:
+	}
+}
+
+// subscribeMulti registers fn against key, lazily creating the
+// (ring buffer + dispatcher) subscription and calling subscribeUnderlying
+// the first time anything subscribes to key. unsubscribe tears the
+// subscription down (including unsubscribeUnderlying on the live
+// websocket) once the last handler for key is removed.
+func subscribeMulti[T any](lc *LiveDataClient, key snapshotKey, fn func(T), subscribeUnderlying func() error, unsubscribeUnderlying func() error) func() {
+	st := lc.multiStream()
+
+	st.mu.Lock()
+	sub, exists := st.subs[key]
+	if !exists {
+		sub = &multiSub{
+			rb:       newRingBuffer[any](64),
+			wake:     make(chan struct{}, 1),
+			stop:     make(chan struct{}),
+			handlers: make(map[int]func(any)),
+		}
+		st.subs[key] = sub
+	}
+	st.mu.Unlock()
+
+	if !exists {
+		lc.wg.Add(1)
+		go func() {
+			defer lc.wg.Done()
+			sub.run(lc.ctx.Done())
+		}()
+		if err := subscribeUnderlying(); err != nil {
+			log.Printf("LiveDataClient: subscribe %+v failed: %v", key, err)
+		}
+	}
+
+	id := sub.addHandler(func(v any) { fn(v.(T)) })
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if sub.removeHandler(id) {
+				st.mu.Lock()
+				delete(st.subs, key)
+				st.mu.Unlock()
+				close(sub.stop)
+				if err := unsubscribeUnderlying(); err != nil {
+					log.Printf("LiveDataClient: unsubscribe %+v failed: %v", key, err)
+				}
+			}
+		})
+	}
+}
+
+// ensureStocksMultiClient lazily connects the shared stocks multi-stream
+// client. Single-attempt only -- see the no-reconnect caveat on
+// multiStreamState.
+func (lc *LiveDataClient) ensureStocksMultiClient() *stream.StocksClient {
+	st := lc.multiStream()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.stocksClient == nil {
+		st.stocksClient = stream.NewStocksClient(marketdata.Feed(""), stream.WithCredentials(AlpacaKey, AlpacaSecret))
+		go func() {
+			if err := st.stocksClient.Connect(lc.ctx); err != nil {
+				log.Printf("LiveDataClient: stocks multi-stream terminated: %v", err)
+			}
+		}()
+	}
+	return st.stocksClient
+}
+
+// ensureOptionsMultiClient lazily connects the shared options multi-stream
+// client. Single-attempt only -- see the no-reconnect caveat on
+// multiStreamState.
+func (lc *LiveDataClient) ensureOptionsMultiClient() *stream.OptionClient {
+	st := lc.multiStream()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.optionsClient == nil {
+		st.optionsClient = stream.NewOptionClient(marketdata.OPRA, stream.WithCredentials(AlpacaKey, AlpacaSecret))
+		go func() {
+			if err := st.optionsClient.Connect(lc.ctx); err != nil {
+				log.Printf("LiveDataClient: options multi-stream terminated: %v", err)
+			}
+		}()
+	}
+	return st.optionsClient
+}
+
+// ensureCryptoMultiClient lazily connects the shared crypto multi-stream
+// client. Single-attempt only -- see the no-reconnect caveat on
+// multiStreamState.
+func (lc *LiveDataClient) ensureCryptoMultiClient() *stream.CryptoClient {
+	st := lc.multiStream()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.cryptoClient == nil {
+		st.cryptoClient = stream.NewCryptoClient(marketdata.US, stream.WithCredentials(AlpacaKey, AlpacaSecret))
+		go func() {
+			if err := st.cryptoClient.Connect(lc.ctx); err != nil {
+				log.Printf("LiveDataClient: crypto multi-stream terminated: %v", err)
+			}
+		}()
+	}
+	return st.cryptoClient
+}
+
+// ensureNewsMultiClient lazily connects the shared news multi-stream
+// client. Single-attempt only -- see the no-reconnect caveat on
+// multiStreamState.
+func (lc *LiveDataClient) ensureNewsMultiClient() *stream.NewsClient {
+	st := lc.multiStream()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.newsClient == nil {
+		st.newsClient = stream.NewNewsClient(stream.WithCredentials(AlpacaKey, AlpacaSecret))
+		go func() {
+			if err := st.newsClient.Connect(lc.ctx); err != nil {
+				log.Printf("LiveDataClient: news multi-stream terminated: %v", err)
+			}
+		}()
+	}
+	return st.newsClient
+}
+
+// OnStockQuote subscribes to quotes for symbol and returns an unsubscribe
+// func.
+func (lc *LiveDataClient) OnStockQuote(symbol string, fn func(stream.Quote)) func() {
+	c := lc.ensureStocksMultiClient()
+	key := snapshotKey{AssetStocks, symbol, MsgQuote}
+	return subscribeMulti(lc, key, fn,
+		func() error {
+			return c.SubscribeToQuotes(func(q stream.Quote) { lc.onMultiMessage(key, q) }, symbol)
+		},
+		func() error { return c.UnsubscribeFromQuotes(symbol) },
+	)
+}
+
+// OnStockTrade subscribes to trades for symbol and returns an unsubscribe
+// func.
+func (lc *LiveDataClient) OnStockTrade(symbol string, fn func(stream.Trade)) func() {
+	c := lc.ensureStocksMultiClient()
+	key := snapshotKey{AssetStocks, symbol, MsgTrade}
+	return subscribeMulti(lc, key, fn,
+		func() error {
+			return c.SubscribeToTrades(func(t stream.Trade) { lc.onMultiMessage(key, t) }, symbol)
+		},
+		func() error { return c.UnsubscribeFromTrades(symbol) },
+	)
+}
+
+// OnStockBar subscribes to minute bars for symbol and returns an
+// unsubscribe func.
+func (lc *LiveDataClient) OnStockBar(symbol string, fn func(stream.Bar)) func() {
+	c := lc.ensureStocksMultiClient()
+	key := snapshotKey{AssetStocks, symbol, MsgBar}
+	return subscribeMulti(lc, key, fn,
+		func() error {
+			return c.SubscribeToBars(func(b stream.Bar) { lc.onMultiMessage(key, b) }, symbol)
+		},
+		func() error { return c.UnsubscribeFromBars(symbol) },
+	)
+}
+
+// OnStockUpdatedBar subscribes to updated (corrected) bars for symbol and
+// returns an unsubscribe func.
+func (lc *LiveDataClient) OnStockUpdatedBar(symbol string, fn func(stream.Bar)) func() {
+	c := lc.ensureStocksMultiClient()
+	key := snapshotKey{AssetStocks, symbol, MsgUpdatedBar}
+	return subscribeMulti(lc, key, fn,
+		func() error {
+			return c.SubscribeToUpdatedBars(func(b stream.Bar) { lc.onMultiMessage(key, b) }, symbol)
+		},
+		func() error { return c.UnsubscribeFromUpdatedBars(symbol) },
+	)
+}
+
+// OnStockStatus subscribes to trading status messages for symbol and
+// returns an unsubscribe func.
+func (lc *LiveDataClient) OnStockStatus(symbol string, fn func(stream.TradingStatus)) func() {
+	c := lc.ensureStocksMultiClient()
+	key := snapshotKey{AssetStocks, symbol, MsgStatus}
+	return subscribeMulti(lc, key, fn,
+		func() error {
+			return c.SubscribeToStatuses(func(s stream.TradingStatus) { lc.onMultiMessage(key, s) }, symbol)
+		},
+		func() error { return c.UnsubscribeFromStatuses(symbol) },
+	)
+}
+
+// OnStockLULD subscribes to Limit Up/Limit Down messages for symbol and
+// returns an unsubscribe func.
+func (lc *LiveDataClient) OnStockLULD(symbol string, fn func(stream.LULD)) func() {
+	c := lc.ensureStocksMultiClient()
+	key := snapshotKey{AssetStocks, symbol, MsgLULD}
+	return subscribeMulti(lc, key, fn,
+		func() error {
+			return c.SubscribeToLULDs(func(l stream.LULD) { lc.onMultiMessage(key, l) }, symbol)
+		},
+		func() error { return c.UnsubscribeFromLULDs(symbol) },
+	)
+}
+
+// OnOptionTrade subscribes to option trades for symbol and returns an
+// unsubscribe func.
+func (lc *LiveDataClient) OnOptionTrade(symbol string, fn func(stream.OptionTrade)) func() {
+	c := lc.ensureOptionsMultiClient()
+	key := snapshotKey{AssetOptions, symbol, MsgTrade}
+	return subscribeMulti(lc, key, fn,
+		func() error {
+			return c.SubscribeToTrades(func(t stream.OptionTrade) { lc.onMultiMessage(key, t) }, symbol)
+		},
+		func() error { return c.UnsubscribeFromTrades(symbol) },
+	)
+}
+
+// OnOptionQuote subscribes to option quotes for symbol and returns an
+// unsubscribe func.
+func (lc *LiveDataClient) OnOptionQuote(symbol string, fn func(stream.OptionQuote)) func() {
+	c := lc.ensureOptionsMultiClient()
+	key := snapshotKey{AssetOptions, symbol, MsgQuote}
+	return subscribeMulti(lc, key, fn,
+		func() error {
+			return c.SubscribeToQuotes(func(q stream.OptionQuote) { lc.onMultiMessage(key, q) }, symbol)
+		},
+		func() error { return c.UnsubscribeFromQuotes(symbol) },
+	)
+}
+
+// OnCryptoTrade subscribes to crypto trades for symbol and returns an
+// unsubscribe func.
+func (lc *LiveDataClient) OnCryptoTrade(symbol string, fn func(stream.CryptoTrade)) func() {
+	c := lc.ensureCryptoMultiClient()
+	key := snapshotKey{AssetCrypto, symbol, MsgTrade}
+	return subscribeMulti(lc, key, fn,
+		func() error {
+			return c.SubscribeToTrades(func(t stream.CryptoTrade) { lc.onMultiMessage(key, t) }, symbol)
+		},
+		func() error { return c.UnsubscribeFromTrades(symbol) },
+	)
+}
+
+// OnCryptoQuote subscribes to crypto quotes for symbol and returns an
+// unsubscribe func.
+func (lc *LiveDataClient) OnCryptoQuote(symbol string, fn func(stream.CryptoQuote)) func() {
+	c := lc.ensureCryptoMultiClient()
+	key := snapshotKey{AssetCrypto, symbol, MsgQuote}
+	return subscribeMulti(lc, key, fn,
+		func() error {
+			return c.SubscribeToQuotes(func(q stream.CryptoQuote) { lc.onMultiMessage(key, q) }, symbol)
+		},
+		func() error { return c.UnsubscribeFromQuotes(symbol) },
+	)
+}
+
+// OnCryptoBar subscribes to crypto minute bars for symbol and returns an
+// unsubscribe func.
+func (lc *LiveDataClient) OnCryptoBar(symbol string, fn func(stream.CryptoBar)) func() {
+	c := lc.ensureCryptoMultiClient()
+	key := snapshotKey{AssetCrypto, symbol, MsgBar}
+	return subscribeMulti(lc, key, fn,
+		func() error {
+			return c.SubscribeToBars(func(b stream.CryptoBar) { lc.onMultiMessage(key, b) }, symbol)
+		},
+		func() error { return c.UnsubscribeFromBars(symbol) },
+	)
+}
+
+// OnNews subscribes to news for symbol ("*" for all symbols, per Alpaca's
+// news stream convention) and returns an unsubscribe func.
+func (lc *LiveDataClient) OnNews(symbol string, fn func(stream.News)) func() {
+	c := lc.ensureNewsMultiClient()
+	key := snapshotKey{AssetNews, symbol, MsgNews}
+	return subscribeMulti(lc, key, fn,
+		func() error {
+			return c.SubscribeToNews(func(n stream.News) { lc.onMultiMessage(key, n) }, symbol)
+		},
+		func() error { return c.UnsubscribeFromNews(symbol) },
+	)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestRingBufferOverflowConcurrent exercises the overflow path (producer
+// eviction) concurrently with pop (consumer eviction) under -race: both can
+// land on the same slot, which is exactly what mu guards against.
+func TestRingBufferOverflowConcurrent(t *testing.T) {
+	rb := newRingBuffer[int](4)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			rb.push(i)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			rb.pop()
+		}
+	}()
+
+	wg.Wait()
+}
+
+func TestRingBufferDropsOldestOnOverflow(t *testing.T) {
+	rb := newRingBuffer[int](2) // rounds up to 2
+
+	rb.push(1)
+	rb.push(2)
+	rb.push(3) // buffer full at 2 slots, evicts 1
+
+	v, ok := rb.pop()
+	if !ok || v != 2 {
+		t.Fatalf("pop() = %v, %v; want 2, true", v, ok)
+	}
+	v, ok = rb.pop()
+	if !ok || v != 3 {
+		t.Fatalf("pop() = %v, %v; want 3, true", v, ok)
+	}
+	if _, ok := rb.pop(); ok {
+		t.Fatalf("pop() on empty buffer returned ok=true")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SymbolDiff describes a change in the tradable-symbol universe between two
+// SymbolManager refreshes.
+type SymbolDiff struct {
+	Added   []string
+	Removed []string
+}
+
+// alpacaAsset mirrors the fields of a single entry in Alpaca's /v2/assets
+// response that SymbolManager filters on.
+type alpacaAsset struct {
+	Symbol       string `json:"symbol"`
+	Exchange     string `json:"exchange"`
+	Status       string `json:"status"`
+	Tradable     bool   `json:"tradable"`
+	Fractionable bool   `json:"fractionable"`
+	Shortable    bool   `json:"shortable"`
+}
+
+// SymbolManager periodically refreshes the tradable-symbol universe from
+// Alpaca's /v2/assets endpoint, filtered by exchange and asset flags
+// (configurable via LoadConfig), and notifies subscribers of additions and
+// removals. This mirrors the "update target symbols daily" pattern used by
+// alpacabkfeeder so long-running processes don't miss newly-listed tickers
+// or waste a subscription slot on delisted ones.
+type SymbolManager struct {
+	mu      sync.RWMutex
+	symbols map[string]bool
+
+	subMu       sync.Mutex
+	subscribers []chan<- SymbolDiff
+
+	interval time.Duration
+	stopCh   chan struct{}
+}
+
+// NewSymbolManager creates a SymbolManager that refreshes every interval.
+// Call Start to begin the background refresh loop.
+func NewSymbolManager(interval time.Duration) *SymbolManager {
+	return &SymbolManager{
+		symbols:  make(map[string]bool),
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Symbols returns a snapshot of the current tradable-symbol universe.
+func (sm *SymbolManager) Symbols() []string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	out := make([]string, 0, len(sm.symbols))
+	for s := range sm.symbols {
+		out = append(out, s)
+	}
+	return out
+}
+
+// Subscribe registers ch to receive a SymbolDiff every time the universe
+// changes after a refresh. Sends don't block: if ch is full, that diff is
+// dropped for ch and logged.
+func (sm *SymbolManager) Subscribe(ch chan<- SymbolDiff) {
+	sm.subMu.Lock()
+	defer sm.subMu.Unlock()
+	sm.subscribers = append(sm.subscribers, ch)
+}
+
+func (sm *SymbolManager) notify(diff SymbolDiff) {
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 {
+		return
+	}
+	sm.subMu.Lock()
+	defer sm.subMu.Unlock()
+	for _, ch := range sm.subscribers {
+		select {
+		case ch <- diff:
+		default:
+			log.Println("SymbolManager: subscriber channel full, dropping diff")
+		}
+	}
+}
+
+// Start performs an initial refresh and then refreshes every sm.interval
+// until Stop is called.
+func (sm *SymbolManager) Start() {
+	if err := sm.refresh(); err != nil {
+		log.Printf("SymbolManager: initial refresh failed: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(sm.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sm.stopCh:
+				return
+			case <-ticker.C:
+				if err := sm.refresh(); err != nil {
+					log.Printf("SymbolManager: refresh failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop ends the background refresh loop.
+func (sm *SymbolManager) Stop() {
+	close(sm.stopCh)
+}
+
+func exchangeAllowed(exchange string) bool {
+	if len(AssetExchanges) == 0 {
+		return true
+	}
+	for _, ex := range AssetExchanges {
+		if ex == exchange {
+			return true
+		}
+	}
+	return false
+}
+
+// assetAllowed reports whether a belongs in the tradable universe under the
+// current AssetExchanges/RequireTradable/RequireFractionable/
+// RequireShortable config, split out of refresh so the filtering logic is
+// testable without a network round trip.
+func assetAllowed(a alpacaAsset) bool {
+	if !exchangeAllowed(a.Exchange) {
+		return false
+	}
+	if RequireTradable && !a.Tradable {
+		return false
+	}
+	if RequireFractionable && !a.Fractionable {
+		return false
+	}
+	if RequireShortable && !a.Shortable {
+		return false
+	}
+	return true
+}
+
+func (sm *SymbolManager) refresh() error {
+	req, err := http.NewRequest(http.MethodGet, "https://api.alpaca.markets/v2/assets?status=active", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("APCA-API-KEY-ID", AlpacaKey)
+	req.Header.Set("APCA-API-SECRET-KEY", AlpacaSecret)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("alpaca assets request failed: %s", resp.Status)
+	}
+
+	var assets []alpacaAsset
+	if err := json.NewDecoder(resp.Body).Decode(&assets); err != nil {
+		return err
+	}
+
+	next := make(map[string]bool)
+	for _, a := range assets {
+		if assetAllowed(a) {
+			next[a.Symbol] = true
+		}
+	}
+
+	sm.mu.Lock()
+	var diff SymbolDiff
+	for s := range next {
+		if !sm.symbols[s] {
+			diff.Added = append(diff.Added, s)
+		}
+	}
+	for s := range sm.symbols {
+		if !next[s] {
+			diff.Removed = append(diff.Removed, s)
+		}
+	}
+	sm.symbols = next
+	sm.mu.Unlock()
+
+	sm.notify(diff)
+	return nil
+}
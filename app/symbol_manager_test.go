@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+// withAssetFilterConfig runs fn with the given filter config installed in
+// the package-level vars SymbolManager's filtering reads, restoring the
+// previous values afterwards.
+func withAssetFilterConfig(t *testing.T, exchanges []string, tradable, fractionable, shortable bool, fn func()) {
+	t.Helper()
+	origExchanges, origTradable, origFractionable, origShortable := AssetExchanges, RequireTradable, RequireFractionable, RequireShortable
+	AssetExchanges, RequireTradable, RequireFractionable, RequireShortable = exchanges, tradable, fractionable, shortable
+	defer func() {
+		AssetExchanges, RequireTradable, RequireFractionable, RequireShortable = origExchanges, origTradable, origFractionable, origShortable
+	}()
+	fn()
+}
+
+func TestExchangeAllowed(t *testing.T) {
+	withAssetFilterConfig(t, nil, false, false, false, func() {
+		if !exchangeAllowed("NYSE") {
+			t.Fatalf("exchangeAllowed(NYSE) = false with no filter, want true")
+		}
+	})
+
+	withAssetFilterConfig(t, []string{"NASDAQ", "NYSE"}, false, false, false, func() {
+		if !exchangeAllowed("NYSE") {
+			t.Fatalf("exchangeAllowed(NYSE) = false, want true (listed)")
+		}
+		if exchangeAllowed("OTC") {
+			t.Fatalf("exchangeAllowed(OTC) = true, want false (not listed)")
+		}
+	})
+}
+
+func TestAssetAllowed(t *testing.T) {
+	base := alpacaAsset{Symbol: "AAPL", Exchange: "NASDAQ", Tradable: true, Fractionable: true, Shortable: true}
+
+	withAssetFilterConfig(t, nil, false, false, false, func() {
+		if !assetAllowed(base) {
+			t.Fatalf("assetAllowed = false with no requirements, want true")
+		}
+	})
+
+	withAssetFilterConfig(t, []string{"NYSE"}, false, false, false, func() {
+		if assetAllowed(base) {
+			t.Fatalf("assetAllowed = true for a NASDAQ asset with only NYSE allowed, want false")
+		}
+	})
+
+	withAssetFilterConfig(t, nil, true, false, false, func() {
+		notTradable := base
+		notTradable.Tradable = false
+		if assetAllowed(notTradable) {
+			t.Fatalf("assetAllowed = true for non-tradable asset with RequireTradable, want false")
+		}
+		if !assetAllowed(base) {
+			t.Fatalf("assetAllowed = false for tradable asset with RequireTradable, want true")
+		}
+	})
+
+	withAssetFilterConfig(t, nil, false, true, false, func() {
+		notFractionable := base
+		notFractionable.Fractionable = false
+		if assetAllowed(notFractionable) {
+			t.Fatalf("assetAllowed = true for non-fractionable asset with RequireFractionable, want false")
+		}
+	})
+
+	withAssetFilterConfig(t, nil, false, false, true, func() {
+		notShortable := base
+		notShortable.Shortable = false
+		if assetAllowed(notShortable) {
+			t.Fatalf("assetAllowed = true for non-shortable asset with RequireShortable, want false")
+		}
+	})
+}